@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Analytics records per-short-ID click counters directly against Redis.
+// Unlike Storage, it is not abstracted behind drivers: the time-bucketed
+// and per-referrer breakdowns lean on Redis hashes/counters that don't
+// have an equivalent in the BoltDB backend yet.
+type Analytics struct {
+	client  *redis.Client
+	context context.Context
+}
+
+// NewAnalytics wraps client for recording and reading click stats.
+func NewAnalytics(client *redis.Client) *Analytics {
+	return &Analytics{client: client, context: context.Background()}
+}
+
+const statsBucketTTL = 35 * 24 * time.Hour
+
+func statsTotalKey(shortId string) string { return "stats:" + shortId + ":total" }
+func statsDayKey(shortId, day string) string {
+	return "stats:" + shortId + ":" + day
+}
+func statsReferrersKey(shortId string) string { return "stats:" + shortId + ":referrers" }
+
+// RecordHit increments the total and today's per-day counters for
+// shortId, and tallies referrer if it's non-empty.
+func (a *Analytics) RecordHit(shortId, referrer string) error {
+	today := time.Now().UTC().Format("2006-01-02")
+
+	pipe := a.client.TxPipeline()
+	pipe.Incr(a.context, statsTotalKey(shortId))
+	pipe.Incr(a.context, statsDayKey(shortId, today))
+	pipe.Expire(a.context, statsDayKey(shortId, today), statsBucketTTL)
+	if referrer != "" {
+		pipe.HIncrBy(a.context, statsReferrersKey(shortId), referrer, 1)
+	}
+
+	_, err := pipe.Exec(a.context)
+	return err
+}
+
+// Stats is the JSON shape returned by GET /stats/{shortId}.
+type Stats struct {
+	Total        uint64            `json:"total"`
+	Last24h      uint64            `json:"last24h"`
+	ByDay        map[string]uint64 `json:"byDay"`
+	TopReferrers []ReferrerCount   `json:"topReferrers"`
+}
+
+// ReferrerCount pairs a referrer with its click count.
+type ReferrerCount struct {
+	Referrer string `json:"referrer"`
+	Count    uint64 `json:"count"`
+}
+
+// statsLookbackDays bounds how many daily buckets Stats reads, since
+// Redis has no built-in way to enumerate only the keys we wrote.
+const statsLookbackDays = 7
+
+// Stats collects the total, last-24h (today's bucket), per-day and
+// top-referrer breakdowns for shortId.
+func (a *Analytics) Stats(shortId string) (Stats, error) {
+	total, err := a.client.Get(a.context, statsTotalKey(shortId)).Uint64()
+	if err != nil && err != redis.Nil {
+		return Stats{}, err
+	}
+
+	byDay := make(map[string]uint64, statsLookbackDays)
+	now := time.Now().UTC()
+	for i := 0; i < statsLookbackDays; i++ {
+		day := now.AddDate(0, 0, -i).Format("2006-01-02")
+		count, err := a.client.Get(a.context, statsDayKey(shortId, day)).Uint64()
+		if err != nil && err != redis.Nil {
+			return Stats{}, err
+		}
+		if count > 0 {
+			byDay[day] = count
+		}
+	}
+
+	referrers, err := a.client.HGetAll(a.context, statsReferrersKey(shortId)).Result()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	top := make([]ReferrerCount, 0, len(referrers))
+	for ref, raw := range referrers {
+		var count uint64
+		if n, err := json.Number(raw).Int64(); err == nil {
+			count = uint64(n)
+		}
+		top = append(top, ReferrerCount{Referrer: ref, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool { return top[i].Count > top[j].Count })
+	if len(top) > 5 {
+		top = top[:5]
+	}
+
+	return Stats{
+		Total:        total,
+		Last24h:      byDay[now.Format("2006-01-02")],
+		ByDay:        byDay,
+		TopReferrers: top,
+	}, nil
+}
+
+// refererFromRequest picks the referrer to attribute a hit to from the
+// standard Referer header; no other header is consulted.
+func refererFromRequest(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get("Referer"))
+}
+
+// statsHandler serves GET /stats/{shortId}.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	shortId := strings.TrimPrefix(r.URL.Path, "/stats/")
+	if shortId == "" {
+		http.Error(w, "shortId is required", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := analytics.Stats(shortId)
+	if err != nil {
+		sugar.Error("Failed to read stats", "shortId", shortId, "error", err)
+		http.Error(w, "Failed to read stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrDecryptionFailed covers every way decrypt can fail: an unknown key
+// id, a malformed envelope, or ciphertext that AES-GCM's authentication
+// tag rejects as tampered. It deliberately doesn't distinguish between
+// those cases to avoid leaking information to callers.
+var ErrDecryptionFailed = errors.New("shortener: failed to decrypt value")
+
+// encryptionKeys maps key id -> raw AES key, loaded once at startup by
+// loadEncryptionKeys. activeKeyId names the key new writes are encrypted
+// with; older key ids stay in the map so existing records remain
+// decryptable after a rotation.
+var (
+	encryptionKeys map[string][]byte
+	activeKeyId    string
+)
+
+// loadEncryptionKeys parses the KEYS env var (e.g.
+// "v1:<hex32>,v2:<hex32>") and ACTIVE_KEY, and fails fast if either is
+// missing or malformed. There is no hardcoded fallback key: the process
+// must not start without real key material configured.
+func loadEncryptionKeys() {
+	raw := os.Getenv("KEYS")
+	if raw == "" {
+		sugar.Fatal("KEYS env var must be set, e.g. KEYS=v1:<64 hex chars>")
+	}
+
+	activeKeyId = os.Getenv("ACTIVE_KEY")
+	if activeKeyId == "" {
+		sugar.Fatal("ACTIVE_KEY env var must name one of the key ids in KEYS")
+	}
+
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(raw, ",") {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			sugar.Fatal("Malformed KEYS entry, expected keyId:hex", "entry", entry)
+		}
+
+		keyBytes, err := hex.DecodeString(parts[1])
+		if err != nil {
+			sugar.Fatal("Failed to decode hex key material", "keyId", parts[0], "error", err)
+		}
+		if _, err := aes.NewCipher(keyBytes); err != nil {
+			sugar.Fatal("Invalid AES key length", "keyId", parts[0], "error", err)
+		}
+
+		keys[parts[0]] = keyBytes
+	}
+
+	if _, ok := keys[activeKeyId]; !ok {
+		sugar.Fatal("ACTIVE_KEY does not match any key id in KEYS", "activeKeyId", activeKeyId)
+	}
+
+	encryptionKeys = keys
+}
+
+// encrypt seals orignalUrl under the active key with AES-GCM and
+// prepends the key id and nonce, so decrypt can find the right key and
+// detect tampering after a rotation.
+func encrypt(orignalUrl string) (result string) {
+	key := encryptionKeys[activeKeyId]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		sugar.Fatal("Failed to create cipher block", "error", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		sugar.Fatal("Failed to create GCM", "error", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		sugar.Fatal("Failed to generate nonce", "error", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(orignalUrl), nil)
+
+	result = activeKeyId + ":" + hex.EncodeToString(sealed)
+	sugar.Debug("URL encrypted successfully", "keyId", activeKeyId, "originalLength", len(orignalUrl))
+	return
+}
+
+// decrypt opens an envelope produced by encrypt, rejecting it with
+// ErrDecryptionFailed if the key id is unknown or the AES-GCM
+// authentication tag doesn't match, rather than returning garbage
+// plaintext to http.Redirect.
+func decrypt(envelope string) (string, error) {
+	keyId, hexPart, found := strings.Cut(envelope, ":")
+	if !found {
+		return "", ErrDecryptionFailed
+	}
+
+	key, ok := encryptionKeys[keyId]
+	if !ok {
+		sugar.Warn("Unknown encryption key id", "keyId", keyId)
+		return "", ErrDecryptionFailed
+	}
+
+	sealed, err := hex.DecodeString(hexPart)
+	if err != nil {
+		return "", ErrDecryptionFailed
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		sugar.Fatal("Failed to create cipher block for decryption", "error", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		sugar.Fatal("Failed to create GCM for decryption", "error", err)
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrDecryptionFailed
+	}
+	nonce, cipherText := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plainText, err := gcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		sugar.Warn("Rejected tampered or undecryptable ciphertext", "keyId", keyId, "error", err)
+		return "", ErrDecryptionFailed
+	}
+
+	sugar.Debug("URL decrypted successfully", "keyId", keyId, "decryptedLength", len(plainText))
+	return string(plainText), nil
+}
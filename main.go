@@ -1,17 +1,12 @@
 package main
 
 import (
-	"context"
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
-	"encoding/hex"
 	"fmt"
 	"math/big"
-	"net"
 	"net/http"
+	"os"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -19,18 +14,30 @@ import (
 )
 
 var (
-	urlStore    = make(map[string]string)
-	secretKey   = []byte("12345678901234567890123456789012")
-	mu          sync.Mutex
 	lettersRune = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 	sugar       *zap.SugaredLogger
+	store       Storage
+	analytics   *Analytics
 )
 
-type RateLimiter struct {
-	client  *redis.Client
-	limit   int
-	window  time.Duration
-	context context.Context
+// newStorage picks the Storage backend based on the STORAGE_BACKEND env
+// var ("redis", the default, or "bolt"). The redis backend reuses the
+// client already used by the rate limiter.
+func newStorage(client *redis.Client) Storage {
+	switch os.Getenv("STORAGE_BACKEND") {
+	case "bolt":
+		path := os.Getenv("BOLT_PATH")
+		if path == "" {
+			path = "shortener.db"
+		}
+		bolt, err := NewBoltStorage(path)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to open BoltDB storage: %v", err))
+		}
+		return bolt
+	default:
+		return NewRedisStorage(client)
+	}
 }
 
 func main() {
@@ -39,7 +46,10 @@ func main() {
 	})
 	defer client.Close()
 
-	rateLimiter := NewRateLimiter(client, 10, 1*time.Minute)
+	rateLimiter := NewRateLimiter(client, 30, 1*time.Minute)
+	rateLimiter.SetRouteLimit("/shorten", 10, 1*time.Minute)
+	store = newStorage(client)
+	analytics = NewAnalytics(client)
 
 	logger, err := zap.NewProduction()
 	if err != nil {
@@ -48,74 +58,22 @@ func main() {
 	defer logger.Sync()
 
 	sugar = logger.Sugar()
+	loadEncryptionKeys()
 	sugar.Info("URL Shortener service starting on port 8080")
 
 	router := http.NewServeMux()
 	router.HandleFunc("/shorten", shortenUrl)
+	router.HandleFunc("/stats/", statsHandler)
 	router.HandleFunc("/", redirectHandler)
+	registerAdminRoutes(router)
 
 	handler := rateLimiterMiddleware(rateLimiter, router)
 
-	http.ListenAndServe(":8080", handler)
-	sugar.Info("Server is running on port 8080")
-}
-
-func (rl *RateLimiter) allow(key string) bool {
-	pipe := rl.client.TxPipeline()
-	incr := pipe.Incr(rl.context, key)
-	pipe.Expire(rl.context, key, rl.window)
-
-	_, err := pipe.Exec(rl.context)
-	if err != nil {
-		return false
-	}
-	return incr.Val() <= int64(rl.limit)
-}
-
-func NewRateLimiter(client *redis.Client, limit int, window time.Duration) *RateLimiter {
-	return &RateLimiter{
-		client:  client,
-		limit:   limit,
-		window:  window,
-		context: context.Background(),
+	if err := serveTLS(handler); err != nil {
+		sugar.Fatal("Server failed", "error", err)
 	}
 }
 
-func rateLimiterMiddleware(rl *RateLimiter, next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clientIP, _, _ := net.SplitHostPort(r.RemoteAddr)
-		if !rl.allow(clientIP) {
-			http.Error(w, "too many request", http.StatusTooManyRequests)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-func encrypt(orignalUrl string) (result string) {
-	block, err := aes.NewCipher(secretKey)
-	if err != nil {
-		sugar.Fatal("Failed to create cipher block", "error", err)
-	}
-
-	plainText := []byte(orignalUrl)
-	cipherText := make([]byte, aes.BlockSize+len(plainText))
-
-	iv := cipherText[:aes.BlockSize]
-
-	if _, err := rand.Read(iv); err != nil {
-		sugar.Fatal("Failed to generate IV", "error", err)
-	}
-
-	stream := cipher.NewCTR(block, iv)
-	stream.XORKeyStream(cipherText[aes.BlockSize:], plainText)
-
-	result = hex.EncodeToString(cipherText)
-	sugar.Debug("URL encrypted successfully", "originalLength", len(orignalUrl))
-	return
-}
-
 // generateShortId Take a number and convert to base 64 to get a random letter or number
 func generateShortId() (result string) {
 	b := make([]rune, 6)
@@ -131,6 +89,25 @@ func generateShortId() (result string) {
 	return
 }
 
+// publicBaseUrl returns the scheme+host to prefix generated short URLs
+// with. It defaults to plain HTTP on :8080 but can be overridden with
+// PUBLIC_BASE_URL once TLS is configured via serveTLS.
+func publicBaseUrl() string {
+	if base := os.Getenv("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// parseTTL parses the optional ?ttl= query parameter (e.g. "24h"). An
+// empty string means the link never expires.
+func parseTTL(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(raw)
+}
+
 func shortenUrl(w http.ResponseWriter, r *http.Request) {
 	orinalUrl := r.URL.Query().Get("url")
 	if orinalUrl == "" {
@@ -145,13 +122,22 @@ func shortenUrl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ttl, err := parseTTL(r.URL.Query().Get("ttl"))
+	if err != nil {
+		sugar.Warn("Invalid ttl parameter", "ttl", r.URL.Query().Get("ttl"))
+		http.Error(w, "ttl parameter must be a valid duration, e.g. 24h", http.StatusBadRequest)
+		return
+	}
+
 	encryptedUrl := encrypt(orinalUrl)
 	shortId := generateShortId()
-	mu.Lock()
-	urlStore[shortId] = encryptedUrl
-	mu.Unlock()
+	if err := store.Put(r.Context(), shortId, encryptedUrl, ttl); err != nil {
+		sugar.Error("Failed to store shortened URL", "error", err)
+		http.Error(w, "Failed to store shortened URL", http.StatusInternalServerError)
+		return
+	}
 
-	shortUrl := fmt.Sprintf("http://localhost:8080/%s", shortId)
+	shortUrl := fmt.Sprintf("%s/%s", publicBaseUrl(), shortId)
 	sugar.Info("URL shortened successfully", "originalUrl", orinalUrl, "shortId", shortId, "shortUrl", shortUrl)
 	fmt.Fprintf(w, "The shortened url is: %s", shortUrl)
 }
@@ -159,38 +145,29 @@ func shortenUrl(w http.ResponseWriter, r *http.Request) {
 func redirectHandler(w http.ResponseWriter, r *http.Request) {
 	shortId := r.URL.Path[1:]
 
-	mu.Lock()
-	encryptedUrl, ok := urlStore[shortId]
-	mu.Unlock()
-
-	if !ok {
+	encryptedUrl, err := store.Get(r.Context(), shortId)
+	if err == ErrNotFound {
 		sugar.Warn("Short ID not found", "shortId", shortId)
 		http.Error(w, "This url does not exist in our project", http.StatusNotFound)
 		return
 	}
-
-	decryptedUrl := decrypt(encryptedUrl)
-	sugar.Info("Redirecting to original URL", "shortId", shortId, "originalUrl", decryptedUrl)
-	http.Redirect(w, r, decryptedUrl, http.StatusFound)
-}
-
-func decrypt(encryptedUrl string) (result string) {
-	block, err := aes.NewCipher(secretKey)
 	if err != nil {
-		sugar.Fatal("Failed to create cipher block for decryption", "error", err)
+		sugar.Error("Failed to look up short ID", "shortId", shortId, "error", err)
+		http.Error(w, "Failed to look up short ID", http.StatusInternalServerError)
+		return
 	}
 
-	cipherText, err := hex.DecodeString(encryptedUrl)
-	if err != nil {
-		sugar.Fatal("Failed to decode hex string", "error", err)
+	if err := analytics.RecordHit(shortId, refererFromRequest(r)); err != nil {
+		sugar.Warn("Failed to record click analytics", "shortId", shortId, "error", err)
 	}
 
-	iv := cipherText[:aes.BlockSize]
-	cipherText = cipherText[aes.BlockSize:]
+	decryptedUrl, err := decrypt(encryptedUrl)
+	if err != nil {
+		sugar.Error("Failed to decrypt stored URL", "shortId", shortId, "error", err)
+		http.Error(w, "Failed to decrypt stored URL", http.StatusInternalServerError)
+		return
+	}
 
-	stream := cipher.NewCTR(block, iv)
-	stream.XORKeyStream(cipherText, cipherText)
-	result = string(cipherText)
-	sugar.Debug("URL decrypted successfully", "decryptedLength", len(result))
-	return
+	sugar.Info("Redirecting to original URL", "shortId", shortId, "originalUrl", decryptedUrl)
+	http.Redirect(w, r, decryptedUrl, http.StatusFound)
 }
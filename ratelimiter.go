@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// routeLimit is the (limit, window) pair enforced for requests matching
+// a particular route prefix.
+type routeLimit struct {
+	limit  int
+	window time.Duration
+}
+
+// RateLimiter is a distributed sliding-window limiter backed by a Redis
+// sorted set per (route, client) pair: each request's timestamp, rejected
+// ones included, is recorded as a member, stale members older than the window are
+// trimmed first, and the remaining cardinality is compared against the
+// configured limit. This avoids the 2x burst a fixed INCR+EXPIRE window
+// allows at window boundaries.
+type RateLimiter struct {
+	client       *redis.Client
+	context      context.Context
+	defaultLimit routeLimit
+	routes       map[string]routeLimit
+}
+
+// NewRateLimiter builds a limiter with a default (limit, window) applied
+// to any route without a more specific entry added via SetRouteLimit.
+func NewRateLimiter(client *redis.Client, limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		client:       client,
+		context:      context.Background(),
+		defaultLimit: routeLimit{limit: limit, window: window},
+		routes:       make(map[string]routeLimit),
+	}
+}
+
+// SetRouteLimit overrides the limit for requests whose path starts with
+// route, e.g. a stricter limit on "/shorten" than on redirect paths.
+func (rl *RateLimiter) SetRouteLimit(route string, limit int, window time.Duration) {
+	rl.routes[route] = routeLimit{limit: limit, window: window}
+}
+
+func (rl *RateLimiter) limitFor(path string) (route string, rt routeLimit) {
+	for prefix, rt := range rl.routes {
+		if strings.HasPrefix(path, prefix) {
+			return prefix, rt
+		}
+	}
+	return "default", rl.defaultLimit
+}
+
+// rateLimitResult carries what the middleware needs to set standard
+// rate-limit response headers.
+type rateLimitResult struct {
+	allowed   bool
+	limit     int
+	remaining int
+	resetAt   time.Time
+}
+
+// allow records one request for key under route's sliding window and
+// reports whether it's within the configured limit.
+func (rl *RateLimiter) allow(route, key string, rt routeLimit) (rateLimitResult, error) {
+	now := time.Now()
+	redisKey := fmt.Sprintf("ratelimit:%s:%s", route, key)
+	windowStart := now.Add(-rt.window)
+
+	member, err := newRateLimitMember(now)
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+
+	pipe := rl.client.TxPipeline()
+	pipe.ZRemRangeByScore(rl.context, redisKey, "0", strconv.FormatInt(windowStart.UnixNano(), 10))
+	pipe.ZAdd(rl.context, redisKey, &redis.Z{Score: float64(now.UnixNano()), Member: member})
+	card := pipe.ZCard(rl.context, redisKey)
+	pipe.Expire(rl.context, redisKey, rt.window)
+
+	if _, err := pipe.Exec(rl.context); err != nil {
+		return rateLimitResult{}, err
+	}
+
+	count := int(card.Val())
+	remaining := rt.limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return rateLimitResult{
+		allowed:   count <= rt.limit,
+		limit:     rt.limit,
+		remaining: remaining,
+		resetAt:   now.Add(rt.window),
+	}, nil
+}
+
+// newRateLimitMember returns a unique sorted-set member for this
+// request so concurrent requests in the same nanosecond don't collide.
+func newRateLimitMember(now time.Time) (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%s", now.UnixNano(), hex.EncodeToString(suffix)), nil
+}
+
+// trustProxy reports whether X-Forwarded-For should be trusted to
+// identify the client, which is only safe when the service sits behind
+// a reverse proxy that sets/overwrites that header itself.
+func trustProxy() bool {
+	return os.Getenv("TRUST_PROXY") == "true"
+}
+
+// clientKey picks the identifier a rate limit is tracked per: the
+// rightmost X-Forwarded-For entry when TRUST_PROXY is enabled (i.e. the
+// service sits behind a trusted reverse proxy), falling back to the TCP
+// peer address otherwise. The rightmost entry is the one the trusted
+// proxy itself appended; proxies append to whatever X-Forwarded-For the
+// client already sent, so the leftmost entry is attacker-controlled and
+// trusting it would let any client pick a fresh rate-limit bucket per
+// request.
+func clientKey(r *http.Request) string {
+	if trustProxy() {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			parts := strings.Split(fwd, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func rateLimiterMiddleware(rl *RateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, rt := rl.limitFor(r.URL.Path)
+
+		result, err := rl.allow(route, clientKey(r), rt)
+		if err != nil {
+			sugar.Error("Rate limiter failed, failing closed", "error", err)
+			http.Error(w, "too many request", http.StatusTooManyRequests)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(result.resetAt.Unix(), 10))
+
+		if !result.allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(result.resetAt).Seconds())))
+			http.Error(w, "too many request", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Storage.Get and Storage.Delete when the
+// requested short id has no matching record (either never created or
+// already expired).
+var ErrNotFound = errors.New("shortener: short id not found")
+
+// Storage is the persistence interface for shortened URL records. It
+// replaces the old in-memory urlStore map so that records can survive
+// process restarts and optionally expire on their own.
+//
+// Implementations must be safe for concurrent use.
+type Storage interface {
+	// Put stores ciphertext under shortId. If ttl is zero the record
+	// never expires.
+	Put(ctx context.Context, shortId, ciphertext string, ttl time.Duration) error
+
+	// Get returns the ciphertext stored under shortId, or ErrNotFound
+	// if it does not exist or has expired.
+	Get(ctx context.Context, shortId string) (string, error)
+
+	// Delete removes the record for shortId. It returns ErrNotFound if
+	// no such record exists.
+	Delete(ctx context.Context, shortId string) error
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var boltUrlsBucket = []byte("urls")
+
+// BoltStorage implements Storage on a local BoltDB file, similar to the
+// approach used by the rushlink project. It is a good fit for
+// single-node deployments that don't want a Redis dependency.
+type BoltStorage struct {
+	db *bbolt.DB
+}
+
+// NewBoltStorage opens (or creates) the BoltDB file at path and prepares
+// its buckets.
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltUrlsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
+
+// encodeBoltRecord packs an expiry (unix nano, 0 meaning "never") and the
+// ciphertext into a single value so TTL can be checked on read without a
+// second bucket.
+func encodeBoltRecord(ciphertext string, expiresAt int64) []byte {
+	buf := make([]byte, 8+len(ciphertext))
+	binary.BigEndian.PutUint64(buf[:8], uint64(expiresAt))
+	copy(buf[8:], ciphertext)
+	return buf
+}
+
+func decodeBoltRecord(raw []byte) (ciphertext string, expiresAt int64) {
+	expiresAt = int64(binary.BigEndian.Uint64(raw[:8]))
+	ciphertext = string(raw[8:])
+	return
+}
+
+func (s *BoltStorage) Put(ctx context.Context, shortId, ciphertext string, ttl time.Duration) error {
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl).UnixNano()
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltUrlsBucket).Put([]byte(shortId), encodeBoltRecord(ciphertext, expiresAt))
+	})
+}
+
+// Get reads shortId via a concurrent-reader db.View first, since the
+// common case is a still-live record and redirects are the hot path.
+// Only when that read turns up an expired record do we pay for a
+// db.Update to evict it.
+func (s *BoltStorage) Get(ctx context.Context, shortId string) (string, error) {
+	var ciphertext string
+	var expired bool
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		raw := tx.Bucket(boltUrlsBucket).Get([]byte(shortId))
+		if raw == nil {
+			return ErrNotFound
+		}
+
+		var expiresAt int64
+		ciphertext, expiresAt = decodeBoltRecord(raw)
+		if expiresAt != 0 && time.Now().UnixNano() > expiresAt {
+			expired = true
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if expired {
+		if err := s.db.Update(func(tx *bbolt.Tx) error {
+			bucket := tx.Bucket(boltUrlsBucket)
+			raw := bucket.Get([]byte(shortId))
+			if raw == nil {
+				return nil
+			}
+			// A Put may have replaced the record since the View above.
+			if _, expiresAt := decodeBoltRecord(raw); expiresAt == 0 || time.Now().UnixNano() <= expiresAt {
+				return nil
+			}
+			return bucket.Delete([]byte(shortId))
+		}); err != nil {
+			return "", err
+		}
+		return "", ErrNotFound
+	}
+
+	return ciphertext, nil
+}
+
+func (s *BoltStorage) Delete(ctx context.Context, shortId string) error {
+	var expired bool
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(boltUrlsBucket)
+		raw := bucket.Get([]byte(shortId))
+		if raw == nil {
+			return ErrNotFound
+		}
+		_, expiresAt := decodeBoltRecord(raw)
+		expired = expiresAt != 0 && time.Now().UnixNano() > expiresAt
+		return bucket.Delete([]byte(shortId))
+	})
+	if err != nil {
+		return err
+	}
+	if expired {
+		return ErrNotFound
+	}
+	return nil
+}
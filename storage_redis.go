@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisStorage implements Storage on top of the existing redis.Client,
+// reusing the same connection the rate limiter uses.
+type RedisStorage struct {
+	client *redis.Client
+}
+
+// NewRedisStorage wraps client as a Storage backend.
+func NewRedisStorage(client *redis.Client) *RedisStorage {
+	return &RedisStorage{client: client}
+}
+
+func urlKey(shortId string) string { return "url:" + shortId }
+
+func (s *RedisStorage) Put(ctx context.Context, shortId, ciphertext string, ttl time.Duration) error {
+	return s.client.Set(ctx, urlKey(shortId), ciphertext, ttl).Err()
+}
+
+func (s *RedisStorage) Get(ctx context.Context, shortId string) (string, error) {
+	val, err := s.client.Get(ctx, urlKey(shortId)).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return val, nil
+}
+
+func (s *RedisStorage) Delete(ctx context.Context, shortId string) error {
+	n, err := s.client.Del(ctx, urlKey(shortId)).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
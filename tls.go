@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// serveTLS starts the HTTPS listener on :443 using Let's Encrypt
+// certificates obtained automatically via ACME, falling back to plain
+// HTTP on :8080 when TLS_HOSTS is not configured. HTTP-01 challenges (and,
+// unless TLS_REDIRECT=false, a redirect to https://) are served on :80 by
+// the autocert manager's HTTPHandler.
+//
+// TLS_HOSTS must be a comma-separated allowlist of hostnames; autocert
+// refuses to request a certificate for any host not on this list, which
+// keeps it from being abused into fetching bogus-SNI certificates.
+func serveTLS(handler http.Handler) error {
+	hostsEnv := os.Getenv("TLS_HOSTS")
+	if hostsEnv == "" {
+		sugar.Info("TLS_HOSTS not set, serving plain HTTP on :8080")
+		return http.ListenAndServe(":8080", handler)
+	}
+
+	hosts := strings.Split(hostsEnv, ",")
+	for i, h := range hosts {
+		hosts[i] = strings.TrimSpace(h)
+	}
+
+	cacheDir := os.Getenv("TLS_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = "certs"
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+
+	httpServer := &http.Server{
+		Addr:    ":80",
+		Handler: manager.HTTPHandler(redirectToHTTPSHandler()),
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil {
+			sugar.Error("HTTP-01 challenge server failed", "error", err)
+		}
+	}()
+
+	tlsServer := &http.Server{
+		Addr:         ":443",
+		Handler:      handler,
+		TLSConfig:    manager.TLSConfig(),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+
+	sugar.Info("Serving HTTPS via autocert", "hosts", hosts)
+	return tlsServer.ListenAndServeTLS("", "")
+}
+
+// redirectToHTTPSHandler redirects any plain HTTP request that isn't an
+// ACME challenge to its https:// equivalent, unless TLS_REDIRECT=false.
+func redirectToHTTPSHandler() http.Handler {
+	if os.Getenv("TLS_REDIRECT") == "false" {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "use https", http.StatusBadRequest)
+		})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := "https://" + r.Host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}